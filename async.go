@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncPolicy selects what happens to a log call when the async buffer is
+// full.
+type AsyncPolicy int
+
+const (
+	// PolicyBlock makes the caller wait for room in the buffer.
+	PolicyBlock AsyncPolicy = iota
+	// PolicyDropOldest discards the oldest buffered record to make room.
+	PolicyDropOldest
+	// PolicyDropNew discards the record that would have been enqueued.
+	PolicyDropNew
+)
+
+// LogAsync decouples log calls from disk I/O: calls enqueue onto a bounded
+// buffer of bufSize records, processed by a single background writer
+// goroutine, so callers never block on rotation or file I/O latency
+// (except under PolicyBlock with a full buffer). onFull selects what
+// happens when the buffer fills. Close drains the buffer before returning.
+func LogAsync(bufSize int, onFull AsyncPolicy) LogOption {
+	return func(l *Logger) {
+		if bufSize < 1 {
+			bufSize = 1
+		}
+		stop := make(chan struct{})
+		as := &asyncState{
+			ch:     make(chan asyncRecord, bufSize),
+			policy: onFull,
+			stop:   stop,
+		}
+		l.async = as
+		as.wg.Add(1)
+		go as.run(l, stop)
+	}
+}
+
+// Stats returns the number of log calls that were enqueued for async
+// writing and the number dropped because the buffer was full. Both are
+// zero for a Logger not configured with LogAsync.
+func (l *Logger) Stats() (enqueued, dropped uint64) {
+	if l.async == nil {
+		return 0, 0
+	}
+	return atomic.LoadUint64(&l.async.enqueued), atomic.LoadUint64(&l.async.dropped)
+}
+
+type asyncRecord struct {
+	lvl Level
+	msg string
+	kv  []interface{}
+}
+
+// asyncState is the buffer and background writer for a Logger in async
+// mode. It is held behind a pointer so that Logger.With shares the same
+// buffer and writer goroutine as its parent.
+type asyncState struct {
+	ch       chan asyncRecord
+	policy   AsyncPolicy
+	mu       sync.Mutex
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	enqueued uint64
+	dropped  uint64
+}
+
+// run is the background writer loop. stop is passed in explicitly, rather
+// than read from as.stop, because close() nils out as.stop (under as.mu) to
+// make a second close() call a no-op; reading the field here would race
+// with that and could select on an already-nil channel.
+func (as *asyncState) run(l *Logger, stop chan struct{}) {
+	defer as.wg.Done()
+	for {
+		select {
+		case rec := <-as.ch:
+			l.writeOut(rec.lvl, rec.msg, rec.kv)
+		case <-stop:
+			as.drain(l)
+			return
+		}
+	}
+}
+
+func (as *asyncState) drain(l *Logger) {
+	for {
+		select {
+		case rec := <-as.ch:
+			l.writeOut(rec.lvl, rec.msg, rec.kv)
+		default:
+			return
+		}
+	}
+}
+
+func (as *asyncState) enqueue(rec asyncRecord) {
+	switch as.policy {
+	case PolicyDropNew:
+		select {
+		case as.ch <- rec:
+			atomic.AddUint64(&as.enqueued, 1)
+		default:
+			atomic.AddUint64(&as.dropped, 1)
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case as.ch <- rec:
+				atomic.AddUint64(&as.enqueued, 1)
+				return
+			default:
+				select {
+				case <-as.ch:
+					atomic.AddUint64(&as.dropped, 1)
+				default:
+				}
+			}
+		}
+	default: // PolicyBlock
+		as.ch <- rec
+		atomic.AddUint64(&as.enqueued, 1)
+	}
+}
+
+// close signals the writer goroutine to drain the buffer and exit, then
+// waits for it to do so. It is safe to call more than once.
+func (as *asyncState) close() {
+	if as == nil {
+		return
+	}
+	as.mu.Lock()
+	stop := as.stop
+	as.stop = nil
+	as.mu.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+	as.wg.Wait()
+}
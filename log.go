@@ -0,0 +1,742 @@
+// Package logger provides a small, dependency-free leveled logger with
+// optional file output and rotation, in the spirit of the loggers used by
+// nats-server and similar infrastructure projects.
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Level identifies the severity of a log line. It is passed to Sink.Write
+// so sinks can apply their own severity mapping (e.g. syslog priority).
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelNotice
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// label returns the text-mode prefix for the level.
+func (lv Level) label() string {
+	switch lv {
+	case LevelNotice:
+		return "[INF]"
+	case LevelWarn:
+		return "[WRN]"
+	case LevelError:
+		return "[ERR]"
+	case LevelFatal:
+		return "[FTL]"
+	case LevelDebug:
+		return "[DBG]"
+	case LevelTrace:
+		return "[TRC]"
+	default:
+		return ""
+	}
+}
+
+// jsonName returns the lowercase level name used in JSON mode.
+func (lv Level) jsonName() string {
+	switch lv {
+	case LevelNotice:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelFatal:
+		return "fatal"
+	case LevelDebug:
+		return "debug"
+	case LevelTrace:
+		return "trace"
+	default:
+		return ""
+	}
+}
+
+// Format selects how log lines are rendered.
+type Format int
+
+const (
+	// FormatText renders lines as "<label> <message>", optionally preceded
+	// by a timestamp and pid. This is the default.
+	FormatText Format = iota
+	// FormatJSON renders each line as a single JSON object with "ts",
+	// "level", "msg", "pid" fields plus any caller-supplied key/value pairs.
+	FormatJSON
+)
+
+// LogOption configures optional Logger behavior at construction time.
+type LogOption func(*Logger)
+
+// LogUTC renders log timestamps in UTC instead of local time.
+func LogUTC(utc bool) LogOption {
+	return func(l *Logger) {
+		l.utc = utc
+		if utc {
+			l.logger.SetFlags(l.logger.Flags() | log.LUTC)
+		} else {
+			l.logger.SetFlags(l.logger.Flags() &^ log.LUTC)
+		}
+	}
+}
+
+// LogFormat selects the rendering used for log lines, FormatText (the
+// default) or FormatJSON.
+func LogFormat(f Format) LogOption {
+	return func(l *Logger) {
+		l.format = f
+	}
+}
+
+// RotationMode selects what happens when a file logger's size limit is
+// reached.
+type RotationMode int
+
+const (
+	// ModeRotate renames the current file to a timestamped backup and
+	// starts a fresh file at the original path. This is the default.
+	ModeRotate RotationMode = iota
+	// ModeTruncate keeps the log at its fixed path: it retains roughly the
+	// last half of the file's content, in place, and discards the rest.
+	// Useful when an external tool tails the fixed path and cannot follow
+	// renamed files.
+	ModeTruncate
+)
+
+// LogRotationMode selects the rotation policy applied when a file logger's
+// size limit (see SetSizeLimit) is reached. It is only meaningful for file
+// loggers created with NewFileLogger; it is a no-op for other Logger
+// instances.
+func LogRotationMode(m RotationMode) LogOption {
+	return func(l *Logger) {
+		if l.fl != nil {
+			l.fl.setRotationMode(m)
+		}
+	}
+}
+
+// LogRotationInterval rotates the current log file on a wall-clock schedule
+// (e.g. time.Hour or 24*time.Hour), independent of any size-based limit set
+// via SetSizeLimit. It is only meaningful for file loggers created with
+// NewFileLogger; it is a no-op for other Logger instances.
+func LogRotationInterval(d time.Duration) LogOption {
+	return func(l *Logger) {
+		if l.fl != nil {
+			l.fl.setRotationInterval(d)
+		}
+	}
+}
+
+// LogCompress gzip-compresses rotated backup files in the background after
+// rotation, producing files named "<backup>.gz". It is only meaningful for
+// file loggers created with NewFileLogger; it is a no-op for other Logger
+// instances.
+func LogCompress(compress bool) LogOption {
+	return func(l *Logger) {
+		if l.fl != nil {
+			l.fl.setCompress(compress)
+		}
+	}
+}
+
+// Logger is a leveled logger that writes to stderr or, when created with
+// NewFileLogger, to a rotating log file.
+type Logger struct {
+	logger  *log.Logger
+	writeMu *sync.Mutex
+	debug   bool
+	trace   bool
+	fl      *fileLogger
+	format  Format
+	utc     bool
+	pid     int
+	fields  []interface{}
+	sinks   *sinkSet
+
+	rl            *rateLimitState
+	sample        uint64
+	sampleCounter *uint64
+
+	async *asyncState
+}
+
+func logFlags(time bool) int {
+	if time {
+		return log.LstdFlags | log.Lmicroseconds
+	}
+	return 0
+}
+
+func pidPrefix(pid bool) string {
+	if pid {
+		return fmt.Sprintf("[%d] ", os.Getpid())
+	}
+	return ""
+}
+
+// NewStdLogger creates a Logger that writes to stderr. The colors argument
+// is accepted for API compatibility with callers that colorize levels on a
+// terminal; this implementation does not itself colorize output.
+func NewStdLogger(time, debug, trace, colors, pid bool, opts ...LogOption) *Logger {
+	l := &Logger{
+		logger:  log.New(os.Stderr, pidPrefix(pid), logFlags(time)),
+		writeMu: &sync.Mutex{},
+		debug:   debug,
+		trace:   trace,
+		pid:     os.Getpid(),
+		sinks:   &sinkSet{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// NewFileLogger creates a Logger that writes to filename. Use SetSizeLimit
+// and SetMaxNumFiles, or the LogRotationInterval option, to enable rotation.
+func NewFileLogger(filename string, time, debug, trace, pid bool, opts ...LogOption) *Logger {
+	fl, err := newFileLogger(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: unable to open log file %q: %v\n", filename, err)
+		os.Exit(1)
+	}
+	l := &Logger{
+		logger:  log.New(fl, pidPrefix(pid), logFlags(time)),
+		writeMu: &sync.Mutex{},
+		debug:   debug,
+		trace:   trace,
+		fl:      fl,
+		pid:     os.Getpid(),
+		sinks:   &sinkSet{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// SetSizeLimit sets the size, in bytes, at which the log file is rotated.
+// A limit of 0 disables size-based rotation. It returns an error if the
+// Logger was not created with NewFileLogger.
+func (l *Logger) SetSizeLimit(limit int64) error {
+	if l.fl == nil {
+		return fmt.Errorf("logger: size limit is only supported by file loggers")
+	}
+	return l.fl.setSizeLimit(limit)
+}
+
+// SetMaxNumFiles sets the number of rotated backup files to retain; older
+// backups beyond this count are removed on each rotation. It returns an
+// error if the Logger was not created with NewFileLogger.
+func (l *Logger) SetMaxNumFiles(n int) error {
+	if l.fl == nil {
+		return fmt.Errorf("logger: max num files is only supported by file loggers")
+	}
+	return l.fl.setMaxNumFiles(n)
+}
+
+// Close releases any resources held by the Logger: it drains any buffered
+// async writes, stops the rate-limit flusher and background rotation,
+// closes the underlying log file, and closes any sinks registered via
+// AddSink.
+func (l *Logger) Close() error {
+	l.rl.close()
+	l.async.close()
+
+	var err error
+	if l.fl != nil {
+		err = l.fl.Close()
+	}
+	if serr := l.sinks.closeAll(); serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+// AddSink registers an additional destination that receives every log line
+// written by l, alongside its primary stderr/file destination.
+func (l *Logger) AddSink(s Sink) {
+	l.sinks.add(s)
+}
+
+// With returns a copy of the Logger that includes kv as additional
+// key/value pairs on every subsequent log call. kv is interpreted as
+// alternating keys (string) and values, as with Noticew. It has no effect
+// in FormatText mode. The returned Logger shares its parent's sinks.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	nl := *l
+	nl.fields = append(append([]interface{}{}, l.fields...), kv...)
+	return &nl
+}
+
+// emit applies sampling and rate limiting (both skipped for LevelFatal, so
+// a fatal message is never silently dropped), then either writes the
+// result immediately or, in async mode, hands it to the background writer.
+func (l *Logger) emit(lvl Level, msg string, kv []interface{}) {
+	if lvl != LevelFatal {
+		if !l.sampleAllow() || !l.rl.allow(lvl) {
+			return
+		}
+		if l.async != nil {
+			l.async.enqueue(asyncRecord{lvl: lvl, msg: msg, kv: kv})
+			return
+		}
+	}
+	l.writeOut(lvl, msg, kv)
+}
+
+// emitDirect writes msg for lvl without applying sampling or rate
+// limiting. It is used for summary lines the rate limiter itself produces,
+// which must not be subject to the very limits they report on.
+func (l *Logger) emitDirect(lvl Level, msg string) {
+	if l.async != nil {
+		l.async.enqueue(asyncRecord{lvl: lvl, msg: msg})
+		return
+	}
+	l.writeOut(lvl, msg, nil)
+}
+
+// writeOut renders msg/kv for lvl and writes it to the primary destination
+// and any registered sinks. Calls to writeOut for a given Logger must not
+// run concurrently with file rotation on the same file; in async mode that
+// is guaranteed by routing all writes through the single writer goroutine.
+func (l *Logger) writeOut(lvl Level, msg string, kv []interface{}) {
+	var line []byte
+	if l.format == FormatJSON {
+		line = l.renderJSON(lvl, msg, kv)
+		// l.logger.Output locks internally; Writer() does not, so JSON mode
+		// (which writes straight to the underlying writer rather than
+		// through Output) needs its own lock to stay safe for a plain
+		// stderr logger, which has no other synchronization of its own.
+		l.writeMu.Lock()
+		l.logger.Writer().Write(line)
+		l.writeMu.Unlock()
+	} else {
+		text := fmt.Sprintf("%s %s", lvl.label(), msg)
+		l.logger.Output(4, text)
+		line = []byte(text)
+	}
+	l.sinks.fanOut(lvl, line)
+}
+
+func (l *Logger) renderJSON(lvl Level, msg string, kv []interface{}) []byte {
+	now := time.Now()
+	if l.utc {
+		now = now.UTC()
+	}
+	fields := make(map[string]interface{}, 4+(len(l.fields)+len(kv))/2)
+	fields["ts"] = now.Format(time.RFC3339Nano)
+	fields["level"] = lvl.jsonName()
+	fields["msg"] = msg
+	fields["pid"] = l.pid
+	addKVFields(fields, l.fields)
+	addKVFields(fields, kv)
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	return append(b, '\n')
+}
+
+// addKVFields merges alternating key/value pairs into fields; non-string
+// keys are ignored.
+func addKVFields(fields map[string]interface{}, kv []interface{}) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			continue
+		}
+		fields[key] = kv[i+1]
+	}
+}
+
+func (l *Logger) output(lvl Level, format string, v ...interface{}) {
+	l.emit(lvl, fmt.Sprintf(format, v...), nil)
+}
+
+// Noticef logs an informational message.
+func (l *Logger) Noticef(format string, v ...interface{}) {
+	l.output(LevelNotice, format, v...)
+}
+
+// Noticew logs an informational message with structured key/value pairs.
+func (l *Logger) Noticew(msg string, kv ...interface{}) {
+	l.emit(LevelNotice, msg, kv)
+}
+
+// Warnf logs a warning message.
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.output(LevelWarn, format, v...)
+}
+
+// Warnw logs a warning message with structured key/value pairs.
+func (l *Logger) Warnw(msg string, kv ...interface{}) {
+	l.emit(LevelWarn, msg, kv)
+}
+
+// Errorf logs an error message.
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.output(LevelError, format, v...)
+}
+
+// Errorw logs an error message with structured key/value pairs.
+func (l *Logger) Errorw(msg string, kv ...interface{}) {
+	l.emit(LevelError, msg, kv)
+}
+
+// exitFunc terminates the process after a fatal log message. It is a
+// variable, rather than a direct os.Exit call, so tests can stub it out
+// instead of killing the test binary.
+var exitFunc = os.Exit
+
+// Fatalf logs a fatal message and terminates the process. In async mode,
+// any previously queued messages are flushed first so the fatal message is
+// never written ahead of log history that preceded it.
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.async.close()
+	l.output(LevelFatal, format, v...)
+	exitFunc(1)
+}
+
+// Fatalw logs a fatal message with structured key/value pairs and
+// terminates the process. In async mode, any previously queued messages
+// are flushed first so the fatal message is never written ahead of log
+// history that preceded it.
+func (l *Logger) Fatalw(msg string, kv ...interface{}) {
+	l.async.close()
+	l.emit(LevelFatal, msg, kv)
+	exitFunc(1)
+}
+
+// Debugf logs a debug message, if debug logging is enabled.
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	if l.debug {
+		l.output(LevelDebug, format, v...)
+	}
+}
+
+// Debugw logs a debug message with structured key/value pairs, if debug
+// logging is enabled.
+func (l *Logger) Debugw(msg string, kv ...interface{}) {
+	if l.debug {
+		l.emit(LevelDebug, msg, kv)
+	}
+}
+
+// Tracef logs a trace message, if trace logging is enabled.
+func (l *Logger) Tracef(format string, v ...interface{}) {
+	if l.trace {
+		l.output(LevelTrace, format, v...)
+	}
+}
+
+// Tracew logs a trace message with structured key/value pairs, if trace
+// logging is enabled.
+func (l *Logger) Tracew(msg string, kv ...interface{}) {
+	if l.trace {
+		l.emit(LevelTrace, msg, kv)
+	}
+}
+
+// fileLogger is an io.Writer over a log file that supports size-based and
+// interval-based rotation, optional background gzip compression of
+// backups, and retention of a bounded number of backups.
+type fileLogger struct {
+	mu           sync.Mutex
+	filename     string
+	file         *os.File
+	size         int64
+	limit        int64
+	maxNumFiles  int
+	mode         RotationMode
+	interval     time.Duration
+	stopRotation chan struct{}
+	compress     bool
+	compressMu   sync.Mutex
+	wg           sync.WaitGroup
+}
+
+func newFileLogger(filename string) (*fileLogger, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileLogger{filename: filename, file: f, size: info.Size()}, nil
+}
+
+func (fl *fileLogger) Write(p []byte) (int, error) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	n, err := fl.file.Write(p)
+	fl.size += int64(n)
+	if err == nil && fl.limit > 0 && fl.size >= fl.limit {
+		if rerr := fl.rotateLocked(); rerr != nil {
+			return n, rerr
+		}
+	}
+	return n, err
+}
+
+// rotateLocked applies fl.mode once the size limit has been reached.
+// fl.mu must be held by the caller.
+func (fl *fileLogger) rotateLocked() error {
+	if fl.mode == ModeTruncate {
+		return fl.truncateRetain()
+	}
+	return fl.rotate()
+}
+
+// rotate closes the current file, renames it with a timestamped suffix, and
+// opens a fresh file at the original path. fl.mu must be held by the caller.
+func (fl *fileLogger) rotate() error {
+	if err := fl.file.Close(); err != nil {
+		return err
+	}
+	backup := fmt.Sprintf("%s.%s", fl.filename, time.Now().Format("2006-01-02T15-04-05"))
+	if err := os.Rename(fl.filename, backup); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fl.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	fl.file = f
+	fl.size = 0
+	if fl.compress {
+		fl.wg.Add(1)
+		go fl.compressBackup(backup)
+	}
+	fl.purge()
+	return nil
+}
+
+// truncateRetain keeps fl.filename at its fixed path: it copies roughly the
+// last half of its content (rounded to the next line boundary, so no line
+// is kept partially) to a temp file, then swaps it in with a rename. The
+// file is only unavailable for writes for the duration of that swap; the
+// tail copy itself happens before the old file handle is touched.
+// fl.mu must be held by the caller.
+func (fl *fileLogger) truncateRetain() error {
+	info, err := fl.file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	start := size / 2
+
+	tail := make([]byte, size-start)
+	if _, err := fl.file.ReadAt(tail, start); err != nil && err != io.EOF {
+		return err
+	}
+	if idx := bytes.IndexByte(tail, '\n'); idx >= 0 {
+		tail = tail[idx+1:]
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fl.filename), filepath.Base(fl.filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(tail); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := fl.file.Close(); err != nil {
+		// fl.filename is untouched; reopen it so the logger keeps working
+		// rather than writing through a closed file handle from here on.
+		os.Remove(tmpName)
+		fl.reopenLocked()
+		return err
+	}
+	if err := os.Rename(tmpName, fl.filename); err != nil {
+		// fl.filename still holds the pre-truncation content; reopen it so
+		// the logger keeps working rather than writing through a closed
+		// file handle from here on.
+		os.Remove(tmpName)
+		fl.reopenLocked()
+		return err
+	}
+	if err := fl.reopenLocked(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// reopenLocked (re)opens fl.filename and updates fl.file/fl.size from its
+// current contents. fl.mu must be held by the caller.
+func (fl *fileLogger) reopenLocked() error {
+	f, err := os.OpenFile(fl.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	fl.file = f
+	fl.size = info.Size()
+	return nil
+}
+
+// compressBackup gzip-compresses the rotated file at path into path+".gz"
+// and removes the uncompressed original. Compression runs off the hot
+// logging path; compressMu serializes it against any other compression in
+// flight so concurrent rotations don't race each other on disk.
+func (fl *fileLogger) compressBackup(path string) {
+	defer fl.wg.Done()
+	fl.compressMu.Lock()
+	defer fl.compressMu.Unlock()
+
+	in, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		gz.Close()
+		out.Close()
+		os.Remove(path + ".gz")
+		return
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return
+	}
+	if err := out.Close(); err != nil {
+		return
+	}
+	os.Remove(path)
+}
+
+// purge removes the oldest rotated backups beyond fl.maxNumFiles.
+// fl.mu must be held by the caller.
+func (fl *fileLogger) purge() {
+	if fl.maxNumFiles <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(fl.filename + ".*")
+	if err != nil {
+		return
+	}
+	sort.Strings(matches)
+	if len(matches) > fl.maxNumFiles {
+		for _, m := range matches[:len(matches)-fl.maxNumFiles] {
+			os.Remove(m)
+		}
+	}
+}
+
+func (fl *fileLogger) setSizeLimit(limit int64) error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.limit = limit
+	return nil
+}
+
+func (fl *fileLogger) setMaxNumFiles(n int) error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.maxNumFiles = n
+	return nil
+}
+
+func (fl *fileLogger) setCompress(c bool) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.compress = c
+}
+
+func (fl *fileLogger) setRotationMode(m RotationMode) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.mode = m
+}
+
+// setRotationInterval starts (or restarts) a background goroutine that
+// rotates the log file every d. d <= 0 disables interval rotation.
+func (fl *fileLogger) setRotationInterval(d time.Duration) {
+	fl.mu.Lock()
+	if fl.stopRotation != nil {
+		close(fl.stopRotation)
+		fl.stopRotation = nil
+	}
+	fl.interval = d
+	if d <= 0 {
+		fl.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	fl.stopRotation = stop
+	fl.mu.Unlock()
+
+	fl.wg.Add(1)
+	go fl.runIntervalRotation(d, stop)
+}
+
+func (fl *fileLogger) runIntervalRotation(d time.Duration, stop chan struct{}) {
+	defer fl.wg.Done()
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			fl.mu.Lock()
+			fl.rotateLocked()
+			fl.mu.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Close stops any background rotation and closes the underlying file.
+func (fl *fileLogger) Close() error {
+	fl.mu.Lock()
+	if fl.stopRotation != nil {
+		close(fl.stopRotation)
+		fl.stopRotation = nil
+	}
+	fl.mu.Unlock()
+
+	fl.wg.Wait()
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.file.Close()
+}
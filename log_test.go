@@ -2,8 +2,16 @@ package logger
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Helper function to create a new standard logger for testing
@@ -36,7 +44,11 @@ func TestNewStdLogger(t *testing.T) {
 	l.Errorf("This is an error-level message")
 	verifyLogOutput(t, buf, "[ERR] This is an error-level message")
 
-	// These lines won't run due to Fatalf above, but are shown for demonstration
+	// Stub exitFunc so Fatalf panics instead of killing the test binary.
+	origExit := exitFunc
+	exitFunc = func(int) { panic("fatal exit") }
+	defer func() { exitFunc = origExit }()
+
 	defer func() {
 		if r := recover(); r == nil {
 			t.Errorf("expected panic on Fatalf, but did not panic")
@@ -63,7 +75,13 @@ func TestLoggerWithUTC(t *testing.T) {
 // Test: File logger creation, file size limit, and file rotation
 func TestLoggerFileRotation(t *testing.T) {
 	tmpFile := "./test_rotate.log"
-	defer os.Remove(tmpFile)
+	defer func() {
+		os.Remove(tmpFile)
+		matches, _ := filepath.Glob(tmpFile + ".*")
+		for _, m := range matches {
+			os.Remove(m)
+		}
+	}()
 
 	l := newTestFileLogger(tmpFile, true, true, true, true)
 	if l == nil {
@@ -128,6 +146,550 @@ func verifyLogOutput(t *testing.T, buf bytes.Buffer, expected string) {
 	}
 }
 
+// memorySink is a test Sink that records every line written to it.
+type memorySink struct {
+	mu     sync.Mutex
+	lines  [][]byte
+	closed bool
+}
+
+func (s *memorySink) Write(_ Level, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, append([]byte(nil), line...))
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Test: ModeTruncate keeps the log at its fixed path, retaining roughly
+// the tail of its content instead of rotating to a new file.
+func TestLoggerRotationModeTruncate(t *testing.T) {
+	tmpFile := "./test_truncate.log"
+	defer os.Remove(tmpFile)
+
+	l := newTestFileLogger(tmpFile, true, true, true, true, LogRotationMode(ModeTruncate))
+	if l == nil {
+		t.Fatal("expected a new file logger, got nil")
+	}
+	defer l.Close()
+
+	if err := l.SetSizeLimit(200); err != nil {
+		t.Fatalf("unexpected error setting size limit: %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		l.Noticef("line number %d of the truncate-mode test", i)
+	}
+
+	matches, err := filepath.Glob(tmpFile + ".*")
+	if err != nil {
+		t.Fatalf("unexpected error globbing for rotated files: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no rotated backup files in ModeTruncate, got: %v", matches)
+	}
+
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("unexpected error reading log file: %v", err)
+	}
+	if len(data) == 0 {
+		t.Fatal("expected the log file to retain content after truncation")
+	}
+	if !bytes.Contains(data, []byte("line number 49")) {
+		t.Errorf("expected the most recent line to survive truncation, got: %s", data)
+	}
+	// Every retained line should be complete: the file must not start
+	// mid-line (it either starts at byte 0 of the original file or right
+	// after a newline).
+	if len(data) > 0 && data[0] == ' ' {
+		t.Errorf("expected truncation to cut on a line boundary, got: %s", data)
+	}
+}
+
+// Test: Async logging with PolicyDropNew drops excess messages under
+// backpressure and reports them via Stats().
+func TestLoggerAsyncBackpressure(t *testing.T) {
+	tmpFile := "./test_async_backpressure.log"
+	defer os.Remove(tmpFile)
+
+	// A small buffer and a blocked writer goroutine (no Noticef drains it
+	// yet) forces the buffer to fill, exercising the drop path.
+	l := newTestFileLogger(tmpFile, true, true, true, true, LogAsync(1, PolicyDropNew))
+	if l == nil {
+		t.Fatal("expected a new file logger, got nil")
+	}
+	defer l.Close()
+
+	for i := 0; i < 50; i++ {
+		l.Noticef("message %d", i)
+	}
+
+	enqueued, dropped := l.Stats()
+	if enqueued+dropped != 50 {
+		t.Errorf("expected enqueued+dropped to account for all 50 calls, got enqueued=%d dropped=%d", enqueued, dropped)
+	}
+}
+
+// Test: Async logging drains pending writes on Close
+func TestLoggerAsyncClose(t *testing.T) {
+	tmpFile := "./test_async_close.log"
+	defer os.Remove(tmpFile)
+
+	l := newTestFileLogger(tmpFile, true, true, true, true, LogAsync(64, PolicyBlock))
+	if l == nil {
+		t.Fatal("expected a new file logger, got nil")
+	}
+
+	for i := 0; i < 20; i++ {
+		l.Noticef("async message %d", i)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error closing logger: %v", err)
+	}
+
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("unexpected error reading log file: %v", err)
+	}
+	if got := bytes.Count(data, []byte("async message")); got != 20 {
+		t.Errorf("expected all 20 async messages drained before Close returned, got %d", got)
+	}
+
+	// A second Close must not panic (e.g. close of an already-closed async
+	// stop channel); an error return from the already-closed file is fine.
+	l.Close()
+}
+
+// Test: Concurrent producers with async logging enabled
+func TestLoggerAsyncConcurrentProducers(t *testing.T) {
+	tmpFile := "./test_async_concurrent.log"
+	defer os.Remove(tmpFile)
+
+	l := newTestFileLogger(tmpFile, true, true, true, true, LogAsync(256, PolicyBlock))
+	if l == nil {
+		t.Fatal("expected a new file logger, got nil")
+	}
+
+	const producers = 10
+	const perProducer = 50
+	var wg sync.WaitGroup
+	wg.Add(producers)
+	for p := 0; p < producers; p++ {
+		go func(id int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				l.Noticef("producer %d message %d", id, i)
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error closing logger: %v", err)
+	}
+
+	enqueued, dropped := l.Stats()
+	if enqueued != producers*perProducer || dropped != 0 {
+		t.Errorf("expected %d enqueued and 0 dropped under PolicyBlock, got enqueued=%d dropped=%d", producers*perProducer, enqueued, dropped)
+	}
+}
+
+// Test: Fatalf flushes previously queued async messages before writing the
+// fatal message itself, so a fatal error never discards log history.
+func TestLoggerFatalfDrainsAsync(t *testing.T) {
+	tmpFile := "./test_async_fatal.log"
+	defer os.Remove(tmpFile)
+
+	l := newTestFileLogger(tmpFile, true, true, true, true, LogAsync(128, PolicyBlock))
+	if l == nil {
+		t.Fatal("expected a new file logger, got nil")
+	}
+
+	origExit := exitFunc
+	exitFunc = func(int) { panic("fatal exit") }
+	defer func() { exitFunc = origExit }()
+
+	for i := 0; i < 100; i++ {
+		l.Noticef("queued message %d", i)
+	}
+
+	func() {
+		defer func() { recover() }()
+		l.Fatalf("fatal error")
+	}()
+
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatalf("unexpected error reading log file: %v", err)
+	}
+	if got := bytes.Count(data, []byte("queued message")); got != 100 {
+		t.Errorf("expected all 100 queued messages flushed before Fatalf wrote, got %d", got)
+	}
+	if !bytes.Contains(data, []byte("fatal error")) {
+		t.Errorf("expected the fatal message to be written, got: %s", data)
+	}
+}
+
+// Test: Rate limiting drops excess messages and reports a suppressed count
+func TestLoggerRateLimit(t *testing.T) {
+	l := newTestStdLogger(true, true, false, false, true, LogRateLimit(map[Level]RateLimit{
+		LevelNotice: {Rate: 1, Per: 20 * time.Millisecond, Burst: 0},
+	}))
+	if l == nil {
+		t.Fatal("expected a new logger, got nil")
+	}
+
+	var buf bytes.Buffer
+	l.logger.SetOutput(&buf)
+
+	for i := 0; i < 5; i++ {
+		l.Noticef("message %d", i)
+	}
+
+	if got := bytes.Count(buf.Bytes(), []byte("message ")); got != 1 {
+		t.Errorf("expected only 1 message to pass the rate limit, got %d in: %s", got, buf.String())
+	}
+
+	// Close triggers a final flush of the suppressed-count summary and
+	// waits for the background flusher goroutine to finish, so the
+	// assertion below can't race with it.
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error closing logger: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("similar messages suppressed")) {
+		t.Errorf("expected a suppressed-count summary line, got: %s", buf.String())
+	}
+}
+
+// Test: Sampling only logs 1 in every N calls
+func TestLoggerSample(t *testing.T) {
+	l := newTestStdLogger(true, true, false, false, true, LogSample(3))
+	if l == nil {
+		t.Fatal("expected a new logger, got nil")
+	}
+
+	var buf bytes.Buffer
+	l.logger.SetOutput(&buf)
+
+	for i := 0; i < 9; i++ {
+		l.Noticef("sampled message %d", i)
+	}
+
+	if got := bytes.Count(buf.Bytes(), []byte("sampled message")); got != 3 {
+		t.Errorf("expected 1 in 3 messages to be logged (3 of 9), got %d in: %s", got, buf.String())
+	}
+}
+
+// Test: Registering a sink fans out log lines in addition to stderr, and
+// Close() closes registered sinks.
+func TestLoggerAddSink(t *testing.T) {
+	l := newTestStdLogger(true, true, false, false, true)
+	if l == nil {
+		t.Fatal("expected a new logger, got nil")
+	}
+
+	var buf bytes.Buffer
+	l.logger.SetOutput(&buf)
+
+	sink := &memorySink{}
+	l.AddSink(sink)
+
+	l.Noticef("fan out to sink")
+
+	sink.mu.Lock()
+	n := len(sink.lines)
+	sink.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("expected 1 line delivered to sink, got %d", n)
+	}
+	if !bytes.Contains(sink.lines[0], []byte("fan out to sink")) {
+		t.Errorf("expected sink line to contain message, got: %s", sink.lines[0])
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error closing logger: %v", err)
+	}
+	if !sink.closed {
+		t.Errorf("expected Close() to close registered sinks")
+	}
+}
+
+// Test: StderrSink writes lines to stderr independent of the Logger's own
+// primary destination.
+func TestStderrSinkWrite(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("unexpected error creating pipe: %v", err)
+	}
+	origStderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = origStderr }()
+
+	sink := NewStderrSink()
+	if err := sink.Write(LevelNotice, []byte("hello from sink\n")); err != nil {
+		t.Fatalf("unexpected error writing to sink: %v", err)
+	}
+	w.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error reading pipe: %v", err)
+	}
+	if !bytes.Contains(data, []byte("hello from sink")) {
+		t.Errorf("expected stderr to contain the written line, got: %s", data)
+	}
+}
+
+// Test: FileSink writes through the same rotation/retention machinery as
+// NewFileLogger.
+func TestFileSinkRotation(t *testing.T) {
+	tmpFile := "./test_filesink.log"
+	defer func() {
+		os.Remove(tmpFile)
+		matches, _ := filepath.Glob(tmpFile + ".*")
+		for _, m := range matches {
+			os.Remove(m)
+		}
+	}()
+
+	sink, err := NewFileSink(tmpFile)
+	if err != nil {
+		t.Fatalf("unexpected error creating file sink: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.SetSizeLimit(1 * 1024); err != nil {
+		t.Fatalf("unexpected error setting size limit: %v", err)
+	}
+	if err := sink.SetMaxNumFiles(2); err != nil {
+		t.Fatalf("unexpected error setting max num files: %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := sink.Write(LevelNotice, []byte(fmt.Sprintf("line %d padded out to force rotation\n", i))); err != nil {
+			t.Fatalf("unexpected error writing to sink: %v", err)
+		}
+	}
+
+	matches, err := filepath.Glob(tmpFile + ".*")
+	if err != nil {
+		t.Fatalf("unexpected error globbing for rotated files: %v", err)
+	}
+	if len(matches) == 0 {
+		t.Errorf("expected at least one rotated backup file, got none")
+	}
+	if len(matches) > 2 {
+		t.Errorf("expected at most 2 retained backups, got %d: %v", len(matches), matches)
+	}
+}
+
+// Test: SyslogSink escapes embedded newlines so a multi-line message still
+// travels as a single RFC 6587-framed record over tcp.
+func TestSyslogSinkEscapesEmbeddedNewlines(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error starting listener: %v", err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	sink, err := NewSyslogSink("tcp", ln.Addr().String(), "testapp")
+	if err != nil {
+		t.Fatalf("unexpected error dialing syslog sink: %v", err)
+	}
+	defer sink.Close()
+
+	conn := <-accepted
+	defer conn.Close()
+
+	if err := sink.Write(LevelError, []byte("line one\nline two\n")); err != nil {
+		t.Fatalf("unexpected error writing to sink: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading from listener: %v", err)
+	}
+	got := buf[:n]
+
+	if bytes.Count(got, []byte("\n")) != 1 {
+		t.Errorf("expected exactly one newline (the record terminator), got: %q", got)
+	}
+	if !bytes.Contains(got, []byte("line one\\nline two")) {
+		t.Errorf("expected the embedded newline to be escaped, got: %q", got)
+	}
+}
+
+// Test: writeJournaldField uses journald's plain KEY=value form when the
+// value has no embedded newline, and its binary length-prefixed form when
+// it does.
+func TestWriteJournaldField(t *testing.T) {
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "MESSAGE", []byte("single line"))
+	if got, want := buf.String(), "MESSAGE=single line\n"; got != want {
+		t.Errorf("expected plain field %q, got %q", want, got)
+	}
+
+	buf.Reset()
+	writeJournaldField(&buf, "MESSAGE", []byte("line one\nline two"))
+	if !bytes.HasPrefix(buf.Bytes(), []byte("MESSAGE\n")) {
+		t.Fatalf("expected binary field to start with \"MESSAGE\\n\", got: %q", buf.Bytes())
+	}
+	rest := buf.Bytes()[len("MESSAGE\n"):]
+	if len(rest) < 8 {
+		t.Fatalf("expected an 8-byte length prefix, got: %q", rest)
+	}
+	length := binary.LittleEndian.Uint64(rest[:8])
+	value := rest[8:]
+	if int(length) != len("line one\nline two") {
+		t.Errorf("expected length prefix %d, got %d", len("line one\nline two"), length)
+	}
+	if string(value) != "line one\nline two\n" {
+		t.Errorf("expected value %q, got %q", "line one\nline two\n", value)
+	}
+}
+
+// Test: Structured JSON output mode, including With() fields
+func TestLoggerJSONFormat(t *testing.T) {
+	l := newTestStdLogger(true, true, false, false, true, LogFormat(FormatJSON))
+	if l == nil {
+		t.Fatal("expected a new logger, got nil")
+	}
+
+	var buf bytes.Buffer
+	l.logger.SetOutput(&buf)
+
+	wl := l.With("component", "api")
+	wl.logger.SetOutput(&buf)
+	wl.Noticew("request handled", "status", 200)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected valid JSON line, got error: %v, body: %s", err, buf.String())
+	}
+
+	for _, field := range []string{"ts", "level", "msg", "pid"} {
+		if _, ok := line[field]; !ok {
+			t.Errorf("expected JSON field %q, got: %v", field, line)
+		}
+	}
+	if line["level"] != "info" {
+		t.Errorf("expected level %q, got %v", "info", line["level"])
+	}
+	if line["component"] != "api" {
+		t.Errorf("expected component %q from With(), got %v", "api", line["component"])
+	}
+	if line["status"] != float64(200) {
+		t.Errorf("expected status 200 from Noticew kv, got %v", line["status"])
+	}
+}
+
+// Test: concurrent JSON-mode writes on a plain stderr logger don't race on
+// the underlying writer (JSON mode bypasses log.Logger's own locking).
+func TestLoggerJSONFormatConcurrent(t *testing.T) {
+	l := newTestStdLogger(true, true, false, false, true, LogFormat(FormatJSON))
+	if l == nil {
+		t.Fatal("expected a new logger, got nil")
+	}
+
+	var buf bytes.Buffer
+	l.logger.SetOutput(&buf)
+
+	var wg sync.WaitGroup
+	const goroutines = 20
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			l.Noticew("concurrent message", "id", id)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// Test: File logger with a wall-clock rotation interval
+func TestLoggerRotationInterval(t *testing.T) {
+	tmpFile := "./test_rotate_interval.log"
+	defer func() {
+		os.Remove(tmpFile)
+		matches, _ := filepath.Glob(tmpFile + ".*")
+		for _, m := range matches {
+			os.Remove(m)
+		}
+	}()
+
+	l := newTestFileLogger(tmpFile, true, true, true, true, LogRotationInterval(20*time.Millisecond))
+	if l == nil {
+		t.Fatal("expected a new file logger, got nil")
+	}
+	defer l.Close()
+
+	l.Noticef("before rotation")
+	time.Sleep(60 * time.Millisecond)
+	l.Noticef("after rotation")
+
+	matches, err := filepath.Glob(tmpFile + ".*")
+	if err != nil {
+		t.Fatalf("unexpected error globbing for rotated files: %v", err)
+	}
+	if len(matches) == 0 {
+		t.Errorf("expected at least one rotated backup file, got none")
+	}
+}
+
+// Test: File logger with background gzip compression of rotated backups
+func TestLoggerCompress(t *testing.T) {
+	tmpFile := "./test_compress.log"
+	defer func() {
+		os.Remove(tmpFile)
+		matches, _ := filepath.Glob(tmpFile + ".*")
+		for _, m := range matches {
+			os.Remove(m)
+		}
+	}()
+
+	l := newTestFileLogger(tmpFile, true, true, true, true, LogCompress(true))
+	if l == nil {
+		t.Fatal("expected a new file logger, got nil")
+	}
+
+	if err := l.SetSizeLimit(10); err != nil {
+		t.Fatalf("unexpected error setting size limit: %v", err)
+	}
+
+	l.Noticef("trigger rotation with a message longer than the size limit")
+	l.Noticef("a second message to make sure rotation already happened")
+
+	// Close waits for any in-flight compression to finish before returning.
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error closing logger: %v", err)
+	}
+
+	matches, err := filepath.Glob(tmpFile + ".*.gz")
+	if err != nil {
+		t.Fatalf("unexpected error globbing for compressed files: %v", err)
+	}
+	if len(matches) == 0 {
+		t.Errorf("expected at least one compressed backup file, got none")
+	}
+}
+
 // Test: Close the logger
 func TestLoggerClose(t *testing.T) {
 	tmpFile := "./test_close.log"
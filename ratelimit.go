@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimit configures a token-bucket limiter for one log level: up to Rate
+// events are permitted per Per, with up to Burst extra allowed to absorb
+// short spikes. Events beyond the limit are dropped and counted; once the
+// limiter's background flusher next runs, a single summary line reporting
+// the drop count is emitted in their place.
+type RateLimit struct {
+	Rate  int
+	Per   time.Duration
+	Burst int
+}
+
+// LogRateLimit applies a per-level token-bucket rate limit to Noticef,
+// Warnf, Errorf, Debugf, Tracef and their *w counterparts. Fatalf/Fatalw
+// are never rate limited, since the process exits immediately after them.
+// Levels not present in perLevel are unaffected.
+func LogRateLimit(perLevel map[Level]RateLimit) LogOption {
+	return func(l *Logger) {
+		if l.rl == nil {
+			l.rl = &rateLimitState{}
+		}
+		l.rl.configure(perLevel, l)
+	}
+}
+
+// LogSample logs roughly 1 in every n calls across all levels (n < 2
+// disables sampling). Sampling is applied independently of, and before,
+// any rate limit.
+func LogSample(n int) LogOption {
+	return func(l *Logger) {
+		if n < 1 {
+			n = 1
+		}
+		l.sample = uint64(n)
+		l.sampleCounter = new(uint64)
+	}
+}
+
+// tokenBucket implements a simple token-bucket limiter and tracks how many
+// events it has suppressed since the last drain.
+type tokenBucket struct {
+	mu         sync.Mutex
+	ratePerSec float64
+	capacity   float64
+	tokens     float64
+	last       time.Time
+	suppressed uint64
+}
+
+func newTokenBucket(rl RateLimit) *tokenBucket {
+	capacity := float64(rl.Rate + rl.Burst)
+	var ratePerSec float64
+	if rl.Per > 0 {
+		ratePerSec = float64(rl.Rate) / rl.Per.Seconds()
+	}
+	return &tokenBucket{
+		ratePerSec: ratePerSec,
+		capacity:   capacity,
+		tokens:     capacity,
+		last:       time.Now(),
+	}
+}
+
+// allow reports whether an event may proceed, consuming a token if so.
+// Otherwise it counts the event as suppressed and returns false.
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.ratePerSec
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	b.suppressed++
+	return false
+}
+
+// drainSuppressed returns and resets the suppressed counter.
+func (b *tokenBucket) drainSuppressed() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := b.suppressed
+	b.suppressed = 0
+	return n
+}
+
+// rateLimitState holds the per-level limiters for a Logger plus the
+// background flusher that emits "N similar messages suppressed" summaries.
+// It is held behind a pointer so that Logger.With shares the same limiters
+// and flusher as its parent rather than spawning its own.
+type rateLimitState struct {
+	mu      sync.Mutex
+	buckets map[Level]*tokenBucket
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (rs *rateLimitState) configure(perLevel map[Level]RateLimit, l *Logger) {
+	rs.mu.Lock()
+	if rs.stop != nil {
+		close(rs.stop)
+		rs.stop = nil
+	}
+	buckets := make(map[Level]*tokenBucket, len(perLevel))
+	interval := time.Second
+	for lvl, rl := range perLevel {
+		buckets[lvl] = newTokenBucket(rl)
+		if rl.Per > 0 && rl.Per < interval {
+			interval = rl.Per
+		}
+	}
+	rs.buckets = buckets
+	if len(buckets) == 0 {
+		rs.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	rs.stop = stop
+	rs.mu.Unlock()
+
+	rs.wg.Add(1)
+	go rs.runFlusher(interval, stop, l)
+}
+
+func (rs *rateLimitState) runFlusher(interval time.Duration, stop chan struct{}, l *Logger) {
+	defer rs.wg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			rs.flush(l)
+		case <-stop:
+			rs.flush(l)
+			return
+		}
+	}
+}
+
+func (rs *rateLimitState) flush(l *Logger) {
+	rs.mu.Lock()
+	buckets := rs.buckets
+	rs.mu.Unlock()
+	for lvl, b := range buckets {
+		if n := b.drainSuppressed(); n > 0 {
+			l.emitDirect(lvl, fmt.Sprintf("%d similar messages suppressed", n))
+		}
+	}
+}
+
+// allow reports whether an event at lvl may proceed. A nil receiver (no
+// rate limit configured) always allows.
+func (rs *rateLimitState) allow(lvl Level) bool {
+	if rs == nil {
+		return true
+	}
+	rs.mu.Lock()
+	b := rs.buckets[lvl]
+	rs.mu.Unlock()
+	if b == nil {
+		return true
+	}
+	return b.allow()
+}
+
+// close stops the background flusher, flushing any pending summaries
+// first, and waits for it to exit.
+func (rs *rateLimitState) close() {
+	if rs == nil {
+		return
+	}
+	rs.mu.Lock()
+	stop := rs.stop
+	rs.stop = nil
+	rs.mu.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+	rs.wg.Wait()
+}
+
+// sample reports whether the call should proceed, consuming one tick of
+// the sample counter. A nil counter (no sampling configured) always
+// allows.
+func (l *Logger) sampleAllow() bool {
+	if l.sampleCounter == nil || l.sample < 2 {
+		return true
+	}
+	c := atomic.AddUint64(l.sampleCounter, 1)
+	return (c-1)%l.sample == 0
+}
@@ -0,0 +1,253 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink is an additional destination a Logger can fan out to via AddSink,
+// on top of its primary stderr/file destination. Write receives the
+// already-rendered line (text or JSON, per the Logger's Format) along with
+// its severity, so sinks that need their own framing (syslog, journald)
+// can derive a priority from level without re-parsing the line.
+type Sink interface {
+	Write(level Level, line []byte) error
+	Close() error
+}
+
+// sinkSet is the mutable, shared collection of sinks registered on a
+// Logger. It is held behind a pointer so that Logger.With can copy a
+// Logger cheaply while still fanning out to the same sinks as its parent.
+type sinkSet struct {
+	mu    sync.RWMutex
+	sinks []Sink
+}
+
+func (s *sinkSet) add(sink Sink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sinks = append(s.sinks, sink)
+}
+
+func (s *sinkSet) fanOut(level Level, line []byte) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sink := range s.sinks {
+		sink.Write(level, line)
+	}
+}
+
+func (s *sinkSet) closeAll() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var err error
+	for _, sink := range s.sinks {
+		if serr := sink.Close(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// StderrSink is a Sink that writes lines to stderr, independent of whatever
+// a Logger's own primary destination is.
+type StderrSink struct{}
+
+// NewStderrSink creates a Sink that writes to stderr.
+func NewStderrSink() *StderrSink {
+	return &StderrSink{}
+}
+
+func (s *StderrSink) Write(_ Level, line []byte) error {
+	_, err := os.Stderr.Write(line)
+	return err
+}
+
+func (s *StderrSink) Close() error {
+	return nil
+}
+
+// FileSink is a Sink that writes lines to a rotating log file, using the
+// same rotation/compression/retention machinery as NewFileLogger.
+type FileSink struct {
+	fl *fileLogger
+}
+
+// NewFileSink creates a Sink that writes to filename. Use its SetSizeLimit,
+// SetMaxNumFiles, and SetCompress methods to configure rotation.
+func NewFileSink(filename string) (*FileSink, error) {
+	fl, err := newFileLogger(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{fl: fl}, nil
+}
+
+func (s *FileSink) Write(_ Level, line []byte) error {
+	_, err := s.fl.Write(line)
+	return err
+}
+
+// SetSizeLimit sets the size, in bytes, at which the sink's file is rotated.
+func (s *FileSink) SetSizeLimit(limit int64) error {
+	return s.fl.setSizeLimit(limit)
+}
+
+// SetMaxNumFiles sets the number of rotated backup files to retain.
+func (s *FileSink) SetMaxNumFiles(n int) error {
+	return s.fl.setMaxNumFiles(n)
+}
+
+// SetCompress enables or disables background gzip compression of rotated
+// backups.
+func (s *FileSink) SetCompress(compress bool) {
+	s.fl.setCompress(compress)
+}
+
+func (s *FileSink) Close() error {
+	return s.fl.Close()
+}
+
+// syslogSeverity maps a Level to an RFC5424 severity.
+func syslogSeverity(level Level) int {
+	switch level {
+	case LevelFatal:
+		return 2 // critical
+	case LevelError:
+		return 3 // error
+	case LevelWarn:
+		return 4 // warning
+	case LevelNotice:
+		return 5 // notice
+	case LevelDebug, LevelTrace:
+		return 7 // debug
+	default:
+		return 6 // informational
+	}
+}
+
+// SyslogSink is a Sink that forwards log lines to a syslog daemon using
+// RFC5424 framing over udp, tcp, or unix.
+type SyslogSink struct {
+	mu       sync.Mutex
+	conn     net.Conn
+	appName  string
+	hostname string
+	facility int
+}
+
+// NewSyslogSink dials a syslog daemon at addr over network ("udp", "tcp",
+// or "unix") and returns a Sink that forwards log lines to it as RFC5424
+// messages tagged with appName.
+func NewSyslogSink(network, addr, appName string) (*SyslogSink, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSink{
+		conn:     conn,
+		appName:  appName,
+		hostname: hostname,
+		facility: 16, // local0, matching common daemon conventions
+	}, nil
+}
+
+func (s *SyslogSink) Write(level Level, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A line with embedded newlines (e.g. a logged stack trace) would
+	// otherwise read to the receiver as several distinct syslog messages,
+	// corrupting RFC 6587 framing over a "tcp" connection. Escape them so
+	// the whole line always travels as a single message.
+	msgText := bytes.ReplaceAll(bytes.TrimRight(line, "\n"), []byte("\n"), []byte("\\n"))
+
+	pri := s.facility*8 + syslogSeverity(level)
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s\n",
+		pri,
+		time.Now().UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		s.appName,
+		os.Getpid(),
+		msgText,
+	)
+	_, err := s.conn.Write([]byte(msg))
+	return err
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.Close()
+}
+
+// journaldSocket is the well-known path systemd-journald listens on.
+const journaldSocket = "/run/systemd/journal/socket"
+
+// JournaldSink is a Sink that forwards log lines to the local
+// systemd-journald socket.
+type JournaldSink struct {
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// NewJournaldSink connects to the local systemd-journald socket and returns
+// a Sink that forwards log lines to it.
+func NewJournaldSink() (*JournaldSink, error) {
+	addr, err := net.ResolveUnixAddr("unixgram", journaldSocket)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldSink{conn: conn}, nil
+}
+
+// writeJournaldField appends one field to buf in journald's native entry
+// format: "KEY=value\n" for a value with no embedded newline, or journald's
+// binary length-prefixed form ("KEY\n" + 8-byte little-endian length +
+// value + "\n") when the value contains one, since the plain form would
+// otherwise be misread as multiple fields.
+func writeJournaldField(buf *bytes.Buffer, key string, value []byte) {
+	if !bytes.ContainsRune(value, '\n') {
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.Write(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteString(key)
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.Write(value)
+	buf.WriteByte('\n')
+}
+
+func (s *JournaldSink) Write(level Level, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "PRIORITY", []byte(fmt.Sprintf("%d", syslogSeverity(level))))
+	writeJournaldField(&buf, "MESSAGE", bytes.TrimRight(line, "\n"))
+
+	_, err := s.conn.Write(buf.Bytes())
+	return err
+}
+
+func (s *JournaldSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.Close()
+}